@@ -0,0 +1,55 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package junit
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		name     string
+		timespec string
+		want     time.Duration
+	}{
+		{name: "empty", timespec: "", want: 0},
+		{name: "bare float seconds", timespec: "1.23", want: 1230 * time.Millisecond},
+		{name: "go duration string", timespec: "1.5s", want: 1500 * time.Millisecond},
+		{name: "iso 8601 minutes and seconds", timespec: "PT1M30S", want: 90 * time.Second},
+		{name: "iso 8601 hours", timespec: "PT2H", want: 2 * time.Hour},
+		{name: "iso 8601 days and hours", timespec: "P1DT1H", want: 25 * time.Hour},
+		{name: "iso 8601 days only", timespec: "P1D", want: 24 * time.Hour},
+		{name: "clock h:mm:ss.s", timespec: "1:23:45.6", want: time.Hour + 23*time.Minute + 45600*time.Millisecond},
+		{name: "locale decimal comma", timespec: "1,23", want: 1230 * time.Millisecond},
+		{name: "thousands separator comma with decimal point", timespec: "1,234.5", want: 1234500 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := duration(tt.timespec)
+			if err != nil {
+				t.Fatalf("duration(%q) returned error: %v", tt.timespec, err)
+			}
+			if got != tt.want {
+				t.Fatalf("duration(%q) = %v, want %v", tt.timespec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDurationParseError(t *testing.T) {
+	_, err := duration("not-a-duration")
+
+	var parseErr *DurationParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("duration() error = %v, want a *DurationParseError", err)
+	}
+	if parseErr.Timespec != "not-a-duration" {
+		t.Fatalf("DurationParseError.Timespec = %q, want %q", parseErr.Timespec, "not-a-duration")
+	}
+}
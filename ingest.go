@@ -6,6 +6,10 @@
 package junit
 
 import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -13,128 +17,393 @@ import (
 
 const timestampLayout = "2006-01-02T15:04:05" // ISO8601
 
-// findSuites performs a depth-first search through the XML document, and
-// attempts to ingest any "testsuite" tags that are encountered.
-func findSuites(nodes []xmlNode, suites chan Suite) {
-	for _, node := range nodes {
-		switch node.XMLName.Local {
-		case "testsuite":
-			suites <- ingestSuite(node)
-		default:
-			findSuites(node.Nodes, suites)
+// findSuites walks the XML token stream produced by dec, and attempts to
+// ingest any top-level "testsuite" elements that are encountered, sending
+// each one on suites as soon as it (and any suites nested within it) has
+// finished parsing.
+func findSuites(dec *xml.Decoder, suites chan Suite, cfg *ingestConfig) error {
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
 		}
+		if err != nil {
+			return err
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "testsuite" {
+			continue
+		}
+
+		suite, err := ingestSuite(dec, start, cfg)
+		if err != nil {
+			return err
+		}
+		suites <- suite
 	}
 }
 
-func ingestSuite(root xmlNode) Suite {
+// ingestSuite consumes tokens from dec, starting immediately after start,
+// until the matching end element for start is reached.
+func ingestSuite(dec *xml.Decoder, start xml.StartElement, cfg *ingestConfig) (Suite, error) {
 	suite := Suite{
-		Name:       root.Attr("name"),
-		Package:    root.Attr("package"),
-		Properties: root.Attrs,
+		Name:       attrOf(start, "name"),
+		Package:    attrOf(start, "package"),
+		Properties: attrMap(start),
 	}
-	if root.Attr("timestamp") != "" {
-		if timestamp, err := time.Parse(timestampLayout, root.Attr("timestamp")); err == nil {
-			suite.Timestamp = timestamp
+	if timestamp := attrOf(start, "timestamp"); timestamp != "" {
+		if ts, err := time.Parse(timestampLayout, timestamp); err == nil {
+			suite.Timestamp = ts
 		}
 	}
 
-	for _, node := range root.Nodes {
-		switch node.XMLName.Local {
-		case "testsuite":
-			testsuite := ingestSuite(node)
-			suite.Suites = append(suite.Suites, testsuite)
-		case "testcase":
-			testcase := ingestTestcase(node)
-			suite.Tests = append(suite.Tests, testcase)
-		case "properties":
-			props := ingestProperties(node)
-			for k, v := range props {
-				suite.Properties[k] = v
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return Suite{}, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "testsuite":
+				testsuite, err := ingestSuite(dec, t, cfg)
+				if err != nil {
+					return Suite{}, err
+				}
+				suite.Suites = append(suite.Suites, testsuite)
+			case "testcase":
+				testcase, err := ingestTestcase(dec, t, cfg)
+				if err != nil {
+					return Suite{}, err
+				}
+				suite.Tests = append(suite.Tests, testcase)
+			case "properties":
+				props, err := ingestProperties(dec, t)
+				if err != nil {
+					return Suite{}, err
+				}
+				for k, v := range props {
+					suite.Properties[k] = v
+				}
+			case "system-out":
+				content, err := ingestContent(dec, t)
+				if err != nil {
+					return Suite{}, err
+				}
+				suite.SystemOut = content
+			case "system-err":
+				content, err := ingestContent(dec, t)
+				if err != nil {
+					return Suite{}, err
+				}
+				suite.SystemErr = content
+			default:
+				if err := dec.Skip(); err != nil {
+					return Suite{}, err
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Local == start.Name.Local {
+				suite.Aggregate()
+				return suite, nil
 			}
-		case "system-out":
-			suite.SystemOut = string(node.Content)
-		case "system-err":
-			suite.SystemErr = string(node.Content)
 		}
 	}
+}
 
-	suite.Aggregate()
+// ingestProperties consumes tokens from dec, starting immediately after
+// start, until the matching end element for start is reached.
+func ingestProperties(dec *xml.Decoder, start xml.StartElement) (map[string]string, error) {
+	props := make(map[string]string)
 
-	return suite
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "property" {
+				props[attrOf(t, "name")] = attrOf(t, "value")
+			}
+			if err := dec.Skip(); err != nil {
+				return nil, err
+			}
+		case xml.EndElement:
+			if t.Name.Local == start.Name.Local {
+				return props, nil
+			}
+		}
+	}
 }
 
-func ingestProperties(root xmlNode) map[string]string {
-	props := make(map[string]string, len(root.Nodes))
+// ingestTestcase consumes tokens from dec, starting immediately after
+// start, until the matching end element for start is reached.
+func ingestTestcase(dec *xml.Decoder, start xml.StartElement, cfg *ingestConfig) (Test, error) {
+	elapsed, err := duration(attrOf(start, "time"))
+	if err != nil && cfg.strictDuration {
+		return Test{}, err
+	}
+
+	test := Test{
+		Name:       attrOf(start, "name"),
+		Classname:  attrOf(start, "classname"),
+		Duration:   elapsed,
+		Status:     StatusPassed,
+		Properties: attrMap(start),
+	}
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return Test{}, err
+		}
 
-	for _, node := range root.Nodes {
-		if node.XMLName.Local == "property" {
-			name := node.Attr("name")
-			value := node.Attr("value")
-			props[name] = value
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "skipped":
+				test.Status = StatusSkipped
+				test.Message = attrOf(t, "message")
+				if err := dec.Skip(); err != nil {
+					return Test{}, err
+				}
+			case "failure":
+				test.Status = StatusFailed
+				test.Message = attrOf(t, "message")
+				test.File = attrOf(t, "file")
+				test.Line = intAttrOf(t, "line")
+				testErr, err := ingestError(dec, t)
+				if err != nil {
+					return Test{}, err
+				}
+				test.Error = testErr
+			case "error":
+				test.Status = StatusError
+				test.Message = attrOf(t, "message")
+				test.File = attrOf(t, "file")
+				test.Line = intAttrOf(t, "line")
+				testErr, err := ingestError(dec, t)
+				if err != nil {
+					return Test{}, err
+				}
+				test.Error = testErr
+			case "rerunFailure", "flakyFailure":
+				retry, err := ingestError(dec, t)
+				if err != nil {
+					return Test{}, err
+				}
+				test.Retries = append(test.Retries, retry)
+			case "system-out":
+				content, err := ingestContent(dec, t)
+				if err != nil {
+					return Test{}, err
+				}
+				test.SystemOut = content
+			case "system-err":
+				content, err := ingestContent(dec, t)
+				if err != nil {
+					return Test{}, err
+				}
+				test.SystemErr = content
+			case "properties":
+				props, err := ingestProperties(dec, t)
+				if err != nil {
+					return Test{}, err
+				}
+				for k, v := range props {
+					test.Properties[k] = v
+				}
+			default:
+				if err := dec.Skip(); err != nil {
+					return Test{}, err
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Local == start.Name.Local {
+				if test.Status == StatusPassed && len(test.Retries) > 0 {
+					test.Flaky = true
+				}
+				return test, nil
+			}
 		}
 	}
+}
 
-	return props
+// ingestError consumes tokens from dec, starting immediately after start,
+// until the matching end element for start is reached.
+func ingestError(dec *xml.Decoder, start xml.StartElement) (Error, error) {
+	content, err := ingestContent(dec, start)
+	if err != nil {
+		return Error{}, err
+	}
+
+	return Error{
+		Body:    content,
+		Type:    attrOf(start, "type"),
+		Message: attrOf(start, "message"),
+	}, nil
 }
 
-func ingestTestcase(root xmlNode) Test {
-	test := Test{
-		Name:       root.Attr("name"),
-		Classname:  root.Attr("classname"),
-		Duration:   duration(root.Attr("time")),
-		Status:     StatusPassed,
-		Properties: root.Attrs,
-	}
-
-	for _, node := range root.Nodes {
-		switch node.XMLName.Local {
-		case "skipped":
-			test.Status = StatusSkipped
-			test.Message = node.Attr("message")
-		case "failure":
-			test.Status = StatusFailed
-			test.Message = node.Attr("message")
-			test.Error = ingestError(node)
-		case "error":
-			test.Status = StatusError
-			test.Message = node.Attr("message")
-			test.Error = ingestError(node)
-		case "system-out":
-			test.SystemOut = string(node.Content)
-		case "system-err":
-			test.SystemErr = string(node.Content)
-		case "properties":
-			props := ingestProperties(node)
-			for k, v := range props {
-				test.Properties[k] = v
+// ingestContent accumulates the character data found between start and its
+// matching end element. The tags of any nested elements are dropped, but
+// the character data inside them is kept.
+func ingestContent(dec *xml.Decoder, start xml.StartElement) (string, error) {
+	var content strings.Builder
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return "", err
+		}
+
+		switch t := tok.(type) {
+		case xml.CharData:
+			content.Write(t)
+		case xml.EndElement:
+			if t.Name.Local == start.Name.Local {
+				return content.String(), nil
 			}
 		}
 	}
+}
 
-	return test
+// attrOf returns the value of the named attribute on start, or the empty
+// string if it isn't present.
+func attrOf(start xml.StartElement, name string) string {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
 }
 
-func ingestError(root xmlNode) Error {
-	return Error{
-		Body:    string(root.Content),
-		Type:    root.Attr("type"),
-		Message: root.Attr("message"),
+// intAttrOf returns the value of the named attribute on start parsed as an
+// int, or 0 if it is absent or not a valid integer.
+func intAttrOf(start xml.StartElement, name string) int {
+	n, err := strconv.Atoi(attrOf(start, name))
+	if err != nil {
+		return 0
 	}
+	return n
+}
+
+// attrMap returns every attribute on start as a name/value map.
+func attrMap(start xml.StartElement) map[string]string {
+	props := make(map[string]string, len(start.Attr))
+	for _, attr := range start.Attr {
+		props[attr.Name.Local] = attr.Value
+	}
+	return props
 }
 
-func duration(timespec string) time.Duration {
-	// Remove commas for larger durations
-	timespec = strings.ReplaceAll(timespec, ",", "")
+var (
+	isoDurationPattern   = regexp.MustCompile(`^P(?:(\d+)D)?T?(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?$`)
+	clockDurationPattern = regexp.MustCompile(`^(\d+):(\d{1,2}):(\d{1,2}(?:\.\d+)?)$`)
+)
+
+// DurationParseError reports that a timespec, such as a <testcase
+// time="..."> attribute, could not be parsed as a duration by any of the
+// formats duration understands.
+type DurationParseError struct {
+	Timespec string
+}
+
+func (e *DurationParseError) Error() string {
+	return fmt.Sprintf("junit: cannot parse duration %q", e.Timespec)
+}
+
+// duration parses timespec as a time.Duration, trying each of the timespec
+// formats seen from real-world JUnit producers in turn: ISO 8601 durations
+// ("PT1M30S"), clock notation ("1:23:45.6"), locale-formatted decimals
+// ("1,23"), bare floating point seconds, and finally Go's own duration
+// syntax ("1.5s"). The first format that parses timespec successfully wins.
+func duration(timespec string) (time.Duration, error) {
+	timespec = strings.TrimSpace(timespec)
+	if timespec == "" {
+		return 0, nil
+	}
+
+	if d, ok := parseISODuration(timespec); ok {
+		return d, nil
+	}
+
+	if d, ok := parseClockDuration(timespec); ok {
+		return d, nil
+	}
+
+	if d, ok := parseLocaleDuration(timespec); ok {
+		return d, nil
+	}
 
-	// Check if there was a valid decimal value
 	if s, err := strconv.ParseFloat(timespec, 64); err == nil {
-		return time.Duration(s * float64(time.Second))
+		return time.Duration(s * float64(time.Second)), nil
 	}
 
-	// Check if there was a valid duration string
 	if d, err := time.ParseDuration(timespec); err == nil {
-		return d
+		return d, nil
 	}
 
-	return 0
+	return 0, &DurationParseError{Timespec: timespec}
+}
+
+func parseISODuration(timespec string) (time.Duration, bool) {
+	match := isoDurationPattern.FindStringSubmatch(timespec)
+	if match == nil || match[1]+match[2]+match[3]+match[4] == "" {
+		return 0, false
+	}
+
+	var seconds float64
+	for i, unit := range []float64{86400, 3600, 60, 1} {
+		if match[i+1] == "" {
+			continue
+		}
+		n, _ := strconv.ParseFloat(match[i+1], 64)
+		seconds += n * unit
+	}
+
+	return time.Duration(seconds * float64(time.Second)), true
+}
+
+func parseClockDuration(timespec string) (time.Duration, bool) {
+	match := clockDurationPattern.FindStringSubmatch(timespec)
+	if match == nil {
+		return 0, false
+	}
+
+	hours, _ := strconv.ParseFloat(match[1], 64)
+	minutes, _ := strconv.ParseFloat(match[2], 64)
+	seconds, _ := strconv.ParseFloat(match[3], 64)
+
+	total := hours*3600 + minutes*60 + seconds
+	return time.Duration(total * float64(time.Second)), true
+}
+
+// parseLocaleDuration treats a single comma with no "." decimal point as a
+// locale decimal separator (e.g. "1,23" meaning 1.23 seconds, as seen from
+// some European-locale JUnit producers). Any other combination of commas --
+// more than one, or one alongside a "." decimal point -- is ambiguous on
+// its own, so it's instead assumed to use the comma as a thousands
+// separator and is stripped before parsing.
+func parseLocaleDuration(timespec string) (time.Duration, bool) {
+	if !strings.Contains(timespec, ",") {
+		return 0, false
+	}
+
+	normalized := timespec
+	if !strings.Contains(timespec, ".") && strings.Count(timespec, ",") == 1 {
+		normalized = strings.Replace(timespec, ",", ".", 1)
+	} else {
+		normalized = strings.ReplaceAll(timespec, ",", "")
+	}
+
+	s, err := strconv.ParseFloat(normalized, 64)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(s * float64(time.Second)), true
 }
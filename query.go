@@ -0,0 +1,422 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package junit
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Query compiles expr into a query, and evaluates it against suites,
+// returning every Test reached by the final "test" step of the expression.
+//
+// Expressions are a compact, XPath-like path over the ingested Suite tree,
+// for example:
+//
+//	//suite[@package="foo"]/test[status="failed" and @retries>0]
+//
+// A leading "//" walks into nested suites recursively rather than only the
+// top-level ones. Predicates are joined with "and", and may compare a field
+// (name, package, classname, status, duration) or a property (prefixed with
+// "@") using "=", "!=", "~=" (regular expression match), or one of the
+// numeric comparators ">", ">=", "<", "<=".
+//
+// Query returns an error if expr does not compile.
+func Query(suites []Suite, expr string) ([]Test, error) {
+	q, err := compileQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.tests(suites), nil
+}
+
+// QuerySuites behaves like Query, but returns the matched Suite values
+// instead of Test values. This is useful for expressions whose final step
+// is "suite" rather than "test".
+func QuerySuites(suites []Suite, expr string) ([]Suite, error) {
+	q, err := compileQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.suites(suites), nil
+}
+
+// query is an expression that has been compiled down to a sequence of steps,
+// ready to be evaluated against a Suite tree.
+type query struct {
+	steps []queryStep
+}
+
+// queryStep is a single "/axis[predicates]" segment of a compiled query.
+type queryStep struct {
+	axis       string // "suite" or "test"
+	recursive  bool   // true if this step was reached via a leading "//"
+	predicates []queryPredicate
+}
+
+// queryPredicate is a single comparison within a step's "[...]" clause.
+type queryPredicate struct {
+	field string // a known field name, or a "@name" property key
+	op    string // "=", "!=", "~=", ">", ">=", "<", "<="
+	value string
+}
+
+var predicatePattern = regexp.MustCompile(`^(@?[\w.\-/]+)\s*(!=|~=|>=|<=|=|>|<)\s*(.+)$`)
+
+// compileQuery parses expr into an AST of steps and predicates.
+func compileQuery(expr string) (*query, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, fmt.Errorf("junit: empty query expression")
+	}
+
+	recursive := false
+	if strings.HasPrefix(expr, "//") {
+		recursive = true
+		expr = expr[2:]
+	} else if strings.HasPrefix(expr, "/") {
+		expr = expr[1:]
+	}
+
+	q := &query{}
+	for i, part := range splitSteps(expr) {
+		step, err := compileStep(part)
+		if err != nil {
+			return nil, err
+		}
+
+		// Only the first step can be reached via a leading "//"; later
+		// steps always walk directly into their parent's children.
+		step.recursive = recursive && i == 0
+
+		q.steps = append(q.steps, step)
+	}
+
+	return q, nil
+}
+
+// splitSteps splits expr on the "/" characters that separate steps,
+// ignoring any "/" that appears inside a "[...]" predicate (including one
+// inside a quoted value), so that property keys and values containing "/"
+// -- such as Ginkgo v2's "github.com/onsi/ginkgo/v2/types.LeafNodeType" --
+// don't get split apart.
+func splitSteps(expr string) []string {
+	var steps []string
+	depth := 0
+	inQuote := false
+	start := 0
+
+	for i, r := range expr {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+		case inQuote:
+			// everything inside a quoted value is left alone
+		case r == '[':
+			depth++
+		case r == ']':
+			if depth > 0 {
+				depth--
+			}
+		case r == '/' && depth == 0:
+			steps = append(steps, expr[start:i])
+			start = i + 1
+		}
+	}
+	steps = append(steps, expr[start:])
+
+	return steps
+}
+
+func compileStep(part string) (queryStep, error) {
+	axis := part
+	predicateBody := ""
+
+	if open := strings.IndexByte(part, '['); open != -1 {
+		if !strings.HasSuffix(part, "]") {
+			return queryStep{}, fmt.Errorf("junit: unterminated predicate in %q", part)
+		}
+		axis = part[:open]
+		predicateBody = part[open+1 : len(part)-1]
+	}
+
+	axis = strings.TrimSpace(axis)
+	if axis != "suite" && axis != "test" {
+		return queryStep{}, fmt.Errorf("junit: unknown query axis %q", axis)
+	}
+
+	step := queryStep{axis: axis}
+	if predicateBody == "" {
+		return step, nil
+	}
+
+	for _, clause := range strings.Split(predicateBody, " and ") {
+		predicate, err := compilePredicate(clause)
+		if err != nil {
+			return queryStep{}, err
+		}
+		step.predicates = append(step.predicates, predicate)
+	}
+
+	return step, nil
+}
+
+func compilePredicate(clause string) (queryPredicate, error) {
+	clause = strings.TrimSpace(clause)
+
+	match := predicatePattern.FindStringSubmatch(clause)
+	if match == nil {
+		return queryPredicate{}, fmt.Errorf("junit: malformed predicate %q", clause)
+	}
+
+	value := strings.TrimSpace(match[3])
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		value = value[1 : len(value)-1]
+	}
+
+	return queryPredicate{
+		field: match[1],
+		op:    match[2],
+		value: value,
+	}, nil
+}
+
+// suites evaluates the compiled query against the given suite tree, and
+// returns the suites selected by the final step, provided it has axis
+// "suite". Steps with axis "test" produce no suites.
+func (q *query) suites(roots []Suite) []Suite {
+	suites, _ := q.run(roots)
+	return suites
+}
+
+// tests evaluates the compiled query against the given suite tree, and
+// returns the tests selected by the final step, provided it has axis
+// "test". Steps with axis "suite" produce no tests.
+func (q *query) tests(roots []Suite) []Test {
+	_, tests := q.run(roots)
+	return tests
+}
+
+func (q *query) run(roots []Suite) ([]Suite, []Test) {
+	candidates := roots
+	descended := false
+
+	for i, step := range q.steps {
+		switch step.axis {
+		case "suite":
+			// The first "suite" step matches directly against roots; any
+			// later one advances into the child suites of whatever the
+			// previous step matched.
+			if descended {
+				candidates = suiteChildren(candidates)
+			}
+			descended = true
+
+			if step.recursive {
+				candidates = flattenSuites(candidates)
+			}
+			candidates = filterSuites(candidates, step.predicates)
+
+			if i == len(q.steps)-1 {
+				return candidates, nil
+			}
+		case "test":
+			pool := candidates
+			if step.recursive {
+				pool = flattenSuites(candidates)
+			}
+
+			tests := testsOf(pool)
+			tests = filterTests(tests, step.predicates)
+
+			if i == len(q.steps)-1 {
+				return nil, tests
+			}
+
+			// A "test" step cannot be followed by anything else, since
+			// tests have no children of their own.
+			return nil, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// suiteChildren returns the direct child suites of every suite in suites,
+// without flattening further than one level.
+func suiteChildren(suites []Suite) []Suite {
+	var children []Suite
+	for _, suite := range suites {
+		children = append(children, suite.Suites...)
+	}
+	return children
+}
+
+// flattenSuites returns every suite reachable from roots, including roots
+// themselves, by walking into nested suites depth-first.
+func flattenSuites(roots []Suite) []Suite {
+	var all []Suite
+	for _, suite := range roots {
+		all = append(all, suite)
+		all = append(all, flattenSuites(suite.Suites)...)
+	}
+	return all
+}
+
+func testsOf(suites []Suite) []Test {
+	var tests []Test
+	for _, suite := range suites {
+		tests = append(tests, suite.Tests...)
+	}
+	return tests
+}
+
+func filterSuites(suites []Suite, predicates []queryPredicate) []Suite {
+	if len(predicates) == 0 {
+		return suites
+	}
+
+	var matched []Suite
+	for _, suite := range suites {
+		if matchesSuite(suite, predicates) {
+			matched = append(matched, suite)
+		}
+	}
+	return matched
+}
+
+func filterTests(tests []Test, predicates []queryPredicate) []Test {
+	if len(predicates) == 0 {
+		return tests
+	}
+
+	var matched []Test
+	for _, test := range tests {
+		if matchesTest(test, predicates) {
+			matched = append(matched, test)
+		}
+	}
+	return matched
+}
+
+func matchesSuite(suite Suite, predicates []queryPredicate) bool {
+	for _, predicate := range predicates {
+		actual, isDuration := suiteField(suite, predicate.field)
+		if !evalPredicate(predicate, actual, isDuration) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesTest(test Test, predicates []queryPredicate) bool {
+	for _, predicate := range predicates {
+		actual, isDuration := testField(test, predicate.field)
+		if !evalPredicate(predicate, actual, isDuration) {
+			return false
+		}
+	}
+	return true
+}
+
+// suiteField resolves a predicate field name against a suite, returning the
+// value to compare against, and whether it should be treated as a duration.
+func suiteField(suite Suite, field string) (string, bool) {
+	if strings.HasPrefix(field, "@") {
+		return suite.Properties[field[1:]], false
+	}
+
+	switch field {
+	case "name":
+		return suite.Name, false
+	case "package":
+		return suite.Package, false
+	default:
+		return "", false
+	}
+}
+
+func testField(test Test, field string) (string, bool) {
+	if strings.HasPrefix(field, "@") {
+		return test.Properties[field[1:]], false
+	}
+
+	switch field {
+	case "name":
+		return test.Name, false
+	case "classname":
+		return test.Classname, false
+	case "status":
+		return string(test.Status), false
+	case "duration":
+		return test.Duration.String(), true
+	default:
+		return "", false
+	}
+}
+
+func evalPredicate(predicate queryPredicate, actual string, isDuration bool) bool {
+	switch predicate.op {
+	case "=":
+		return actual == predicate.value
+	case "!=":
+		return actual != predicate.value
+	case "~=":
+		re, err := regexp.Compile(predicate.value)
+		return err == nil && re.MatchString(actual)
+	case ">", ">=", "<", "<=":
+		return evalNumericPredicate(predicate, actual, isDuration)
+	default:
+		return false
+	}
+}
+
+func evalNumericPredicate(predicate queryPredicate, actual string, isDuration bool) bool {
+	left, ok := numericValue(actual, isDuration)
+	if !ok {
+		return false
+	}
+
+	right, ok := numericValue(predicate.value, isDuration)
+	if !ok {
+		return false
+	}
+
+	switch predicate.op {
+	case ">":
+		return left > right
+	case ">=":
+		return left >= right
+	case "<":
+		return left < right
+	case "<=":
+		return left <= right
+	default:
+		return false
+	}
+}
+
+// numericValue resolves a raw string to a float64 for comparison, parsing it
+// as a duration (in seconds) when the field it came from is duration-typed.
+func numericValue(raw string, isDuration bool) (float64, bool) {
+	if isDuration {
+		d, err := duration(raw)
+		if err != nil {
+			return 0, false
+		}
+		return d.Seconds(), true
+	}
+
+	f, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, false
+	}
+	return f, true
+}
@@ -0,0 +1,190 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package junit
+
+import (
+	"testing"
+	"time"
+)
+
+func testTree() []Suite {
+	inner := Suite{
+		Name:    "inner",
+		Package: "pkg.inner",
+		Tests: []Test{
+			{Name: "inner-passed", Status: StatusPassed},
+			{Name: "inner-failed", Status: StatusFailed, Duration: 2 * time.Second},
+		},
+	}
+
+	root := Suite{
+		Name:    "root",
+		Package: "foo",
+		Suites:  []Suite{inner},
+		Tests: []Test{
+			{Name: "root-passed", Status: StatusPassed, Properties: map[string]string{"retries": "0"}},
+			{
+				Name:       "root-flaky",
+				Status:     StatusPassed,
+				Properties: map[string]string{"retries": "1"},
+			},
+		},
+	}
+
+	return []Suite{root}
+}
+
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		want []string // test names, in order
+	}{
+		{
+			name: "top level tests",
+			expr: "suite/test",
+			want: []string{"root-passed", "root-flaky"},
+		},
+		{
+			name: "recursive descent finds nested test",
+			expr: "//test[status=\"failed\"]",
+			want: []string{"inner-failed"},
+		},
+		{
+			name: "numeric predicate on property",
+			expr: "//test[status=\"passed\" and @retries>0]",
+			want: []string{"root-flaky"},
+		},
+		{
+			name: "numeric comparator on duration",
+			expr: "//test[duration>1s]",
+			want: []string{"inner-failed"},
+		},
+		{
+			name: "no match",
+			expr: "//test[status=\"error\"]",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Query(testTree(), tt.expr)
+			if err != nil {
+				t.Fatalf("Query(%q) returned error: %v", tt.expr, err)
+			}
+
+			var names []string
+			for _, test := range got {
+				names = append(names, test.Name)
+			}
+
+			if !equalStrings(names, tt.want) {
+				t.Fatalf("Query(%q) = %v, want %v", tt.expr, names, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuerySuites(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		want []string // suite names, in order
+	}{
+		{
+			name: "top level suite",
+			expr: "suite[package=\"foo\"]",
+			want: []string{"root"},
+		},
+		{
+			name: "descend into child suite",
+			expr: "/suite/suite",
+			want: []string{"inner"},
+		},
+		{
+			name: "descend into a specific child suite",
+			expr: "/suite[package=\"foo\"]/suite[package=\"pkg.inner\"]",
+			want: []string{"inner"},
+		},
+		{
+			name: "descend into a child suite that doesn't exist",
+			expr: "/suite[package=\"foo\"]/suite[package=\"pkg.missing\"]",
+			want: nil,
+		},
+		{
+			name: "recursive descent from the root",
+			expr: "//suite",
+			want: []string{"root", "inner"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := QuerySuites(testTree(), tt.expr)
+			if err != nil {
+				t.Fatalf("QuerySuites(%q) returned error: %v", tt.expr, err)
+			}
+
+			var names []string
+			for _, suite := range got {
+				names = append(names, suite.Name)
+			}
+
+			if !equalStrings(names, tt.want) {
+				t.Fatalf("QuerySuites(%q) = %v, want %v", tt.expr, names, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryPropertyWithSlash(t *testing.T) {
+	suites := []Suite{
+		{
+			Name: "root",
+			Tests: []Test{
+				{
+					Name:   "spec",
+					Status: StatusPassed,
+					Properties: map[string]string{
+						"github.com/onsi/ginkgo/v2/types.LeafNodeType": "It",
+					},
+				},
+			},
+		},
+	}
+
+	got, err := Query(suites, `//test[@github.com/onsi/ginkgo/v2/types.LeafNodeType="It"]`)
+	if err != nil {
+		t.Fatalf("Query() returned error: %v", err)
+	}
+
+	if len(got) != 1 || got[0].Name != "spec" {
+		t.Fatalf("Query() = %v, want a single match named %q", got, "spec")
+	}
+}
+
+func TestQueryCompileError(t *testing.T) {
+	if _, err := Query(nil, ""); err == nil {
+		t.Fatal("Query(\"\") should have returned an error")
+	}
+
+	if _, err := Query(nil, "suite[status=\"failed\""); err == nil {
+		t.Fatal("Query() with an unterminated predicate should have returned an error")
+	}
+}
+
+func equalStrings(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package junit
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// IngestFile parses the JUnit formatted XML found at filename, and returns
+// any suites that were found.
+func IngestFile(filename string, opts ...IngestOption) ([]Suite, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return IngestReader(file, opts...)
+}
+
+// IngestDir walks dir looking for files with a ".xml" extension, and
+// ingests each one it finds.
+func IngestDir(dir string, opts ...IngestOption) ([]Suite, error) {
+	var suites []Suite
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(path) != ".xml" {
+			return nil
+		}
+
+		found, err := IngestFile(path, opts...)
+		if err != nil {
+			return err
+		}
+		suites = append(suites, found...)
+
+		return nil
+	})
+
+	return suites, err
+}
+
+// IngestReader parses the given io.Reader for JUnit formatted XML, and
+// returns any suites that were found. Unlike IngestReaderStream, the whole
+// document is consumed before any suites are returned.
+func IngestReader(r io.Reader, opts ...IngestOption) ([]Suite, error) {
+	rawSuites, errs := IngestReaderStream(r, opts...)
+
+	var suites []Suite
+	for suite := range rawSuites {
+		suites = append(suites, suite)
+	}
+
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+
+	return suites, nil
+}
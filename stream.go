@@ -0,0 +1,62 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package junit
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+// ingestConfig holds the options assembled from a caller's IngestOption
+// values.
+type ingestConfig struct {
+	strictDuration bool
+}
+
+// IngestOption customizes the behavior of IngestReaderStream.
+type IngestOption func(*ingestConfig)
+
+// WithStrictDurationParsing causes IngestReaderStream to fail with a
+// DurationParseError as soon as it encounters a <testcase time="...">
+// attribute that none of the supported timespec formats can parse, instead
+// of silently treating it as a zero duration. No other attribute is parsed
+// as a duration.
+func WithStrictDurationParsing() IngestOption {
+	return func(cfg *ingestConfig) {
+		cfg.strictDuration = true
+	}
+}
+
+// IngestReaderStream parses the given io.Reader for JUnit formatted XML, and
+// emits each top-level "testsuite" element on the returned channel as soon
+// as it (and any suites nested within it) has finished parsing, rather than
+// buffering the whole document up front the way IngestReader does. This
+// keeps memory use bounded when processing very large reports, such as the
+// consolidated output that Ginkgo v2 can produce.
+//
+// The suites channel is closed once the document has been fully consumed or
+// an error is encountered. At most one error is ever sent on the returned
+// error channel, after which it too is closed.
+func IngestReaderStream(r io.Reader, opts ...IngestOption) (<-chan Suite, <-chan error) {
+	cfg := &ingestConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	suites := make(chan Suite)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(suites)
+		defer close(errs)
+
+		if err := findSuites(xml.NewDecoder(r), suites, cfg); err != nil {
+			errs <- err
+		}
+	}()
+
+	return suites, errs
+}
@@ -0,0 +1,84 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package junit
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const sampleReport = `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+	<testsuite name="root" package="foo" tests="2">
+		<testcase name="passing" classname="pkg" time="1.5"></testcase>
+		<testcase name="failing" classname="pkg" time="0.5">
+			<failure message="boom" type="AssertionError">stack trace</failure>
+		</testcase>
+	</testsuite>
+	<testsuite name="other" package="bar" tests="1">
+		<testcase name="skipped" classname="pkg" time="0">
+			<skipped message="not run"></skipped>
+		</testcase>
+	</testsuite>
+</testsuites>
+`
+
+func TestIngestReaderStream(t *testing.T) {
+	suites, errs := IngestReaderStream(strings.NewReader(sampleReport))
+
+	var names []string
+	for suite := range suites {
+		names = append(names, suite.Name)
+	}
+
+	if err := <-errs; err != nil {
+		t.Fatalf("IngestReaderStream() returned error: %v", err)
+	}
+
+	want := []string{"root", "other"}
+	if !equalStrings(names, want) {
+		t.Fatalf("IngestReaderStream() suites = %v, want %v", names, want)
+	}
+}
+
+func TestIngestReaderStreamStrictDuration(t *testing.T) {
+	report := `<testsuite name="root"><testcase name="bad-time" time="not-a-duration"></testcase></testsuite>`
+
+	suites, errs := IngestReaderStream(strings.NewReader(report), WithStrictDurationParsing())
+
+	for range suites {
+		// drain so the producer goroutine can finish
+	}
+
+	err := <-errs
+	if err == nil {
+		t.Fatal("IngestReaderStream() with WithStrictDurationParsing() should have returned an error")
+	}
+
+	var parseErr *DurationParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("IngestReaderStream() error = %v, want a *DurationParseError", err)
+	}
+}
+
+func TestIngestReader(t *testing.T) {
+	suites, err := IngestReader(strings.NewReader(sampleReport))
+	if err != nil {
+		t.Fatalf("IngestReader() returned error: %v", err)
+	}
+
+	if len(suites) != 2 {
+		t.Fatalf("IngestReader() returned %d suites, want 2", len(suites))
+	}
+
+	if got, want := suites[0].Totals.Tests, 2; got != want {
+		t.Fatalf("suites[0].Totals.Tests = %d, want %d", got, want)
+	}
+	if got, want := suites[0].Totals.Failed, 1; got != want {
+		t.Fatalf("suites[0].Totals.Failed = %d, want %d", got, want)
+	}
+}
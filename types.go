@@ -0,0 +1,123 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package junit
+
+import "time"
+
+// Status is the outcome of running a single Test.
+type Status string
+
+// The full set of outcomes a Test can have.
+const (
+	StatusPassed  Status = "passed"
+	StatusSkipped Status = "skipped"
+	StatusFailed  Status = "failed"
+	StatusError   Status = "error"
+)
+
+// Suite represents a single JUnit "testsuite" element, along with any
+// testcases or nested suites that it contains.
+type Suite struct {
+	Name       string
+	Package    string
+	Properties map[string]string
+	Tests      []Test
+	Suites     []Suite
+	SystemOut  string
+	SystemErr  string
+	Timestamp  time.Time
+	Totals     Totals
+}
+
+// Test represents a single JUnit "testcase" element.
+type Test struct {
+	Name       string
+	Classname  string
+	Duration   time.Duration
+	Status     Status
+	Message    string
+	Error      Error
+	Properties map[string]string
+	SystemOut  string
+	SystemErr  string
+
+	// Retries holds one entry per "rerunFailure"/"flakyFailure" element
+	// recorded against the test, in document order.
+	Retries []Error
+
+	// Flaky is true when the test ultimately passed, but only after one
+	// or more recorded retries.
+	Flaky bool
+
+	// File and Line locate the assertion responsible for a failure or
+	// error, when the producer reported them.
+	File string
+	Line int
+}
+
+// Error represents the content of a "failure", "error", "rerunFailure", or
+// "flakyFailure" element.
+type Error struct {
+	Body    string
+	Type    string
+	Message string
+}
+
+// Totals tallies the number, and combined duration, of tests within a
+// Suite, broken down by their Status.
+type Totals struct {
+	Suites   int
+	Tests    int
+	Passed   int
+	Skipped  int
+	Failed   int
+	Error    int
+	Flaky    int
+	Duration time.Duration
+}
+
+// Aggregate recomputes suite.Totals from the suite's own tests, together
+// with the already-aggregated totals of any nested suites. It should be
+// called after a Suite's Tests and Suites fields have been populated.
+func (suite *Suite) Aggregate() {
+	var totals Totals
+	totals.Suites = 1
+
+	for _, test := range suite.Tests {
+		totals.Tests++
+		totals.Duration += test.Duration
+
+		switch test.Status {
+		case StatusPassed:
+			totals.Passed++
+		case StatusSkipped:
+			totals.Skipped++
+		case StatusFailed:
+			totals.Failed++
+		case StatusError:
+			totals.Error++
+		}
+
+		// A flaky test is still counted as Passed above; Flaky layers on
+		// top of that to flag that it only passed after a retry.
+		if test.Flaky {
+			totals.Flaky++
+		}
+	}
+
+	for _, nested := range suite.Suites {
+		totals.Suites += nested.Totals.Suites
+		totals.Tests += nested.Totals.Tests
+		totals.Passed += nested.Totals.Passed
+		totals.Skipped += nested.Totals.Skipped
+		totals.Failed += nested.Totals.Failed
+		totals.Error += nested.Totals.Error
+		totals.Flaky += nested.Totals.Flaky
+		totals.Duration += nested.Totals.Duration
+	}
+
+	suite.Totals = totals
+}
@@ -0,0 +1,175 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package junit
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+)
+
+// WriteXML serializes suites back to JUnit formatted XML, writing the
+// result to w. It round-trips Status, Message, Error (body/type), Retries,
+// File/Line, and SystemOut/SystemErr. It does not emit Properties, and it
+// always writes Retries as "rerunFailure" elements, so a retry originally
+// ingested from a "flakyFailure" element changes tag name on round-trip.
+func WriteXML(w io.Writer, suites []Suite) error {
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "\t")
+
+	for _, suite := range suites {
+		if err := writeSuite(enc, suite); err != nil {
+			return err
+		}
+	}
+
+	return enc.Flush()
+}
+
+func writeSuite(enc *xml.Encoder, suite Suite) error {
+	start := xml.StartElement{Name: xml.Name{Local: "testsuite"}, Attr: suiteAttrs(suite)}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+
+	for _, test := range suite.Tests {
+		if err := writeTest(enc, test); err != nil {
+			return err
+		}
+	}
+
+	for _, nested := range suite.Suites {
+		if err := writeSuite(enc, nested); err != nil {
+			return err
+		}
+	}
+
+	if err := writeTextElement(enc, "system-out", suite.SystemOut); err != nil {
+		return err
+	}
+	if err := writeTextElement(enc, "system-err", suite.SystemErr); err != nil {
+		return err
+	}
+
+	return enc.EncodeToken(xml.EndElement{Name: start.Name})
+}
+
+func suiteAttrs(suite Suite) []xml.Attr {
+	attrs := []xml.Attr{
+		{Name: xml.Name{Local: "name"}, Value: suite.Name},
+		{Name: xml.Name{Local: "package"}, Value: suite.Package},
+	}
+	if !suite.Timestamp.IsZero() {
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "timestamp"}, Value: suite.Timestamp.Format(timestampLayout)})
+	}
+	return attrs
+}
+
+func writeTest(enc *xml.Encoder, test Test) error {
+	start := xml.StartElement{
+		Name: xml.Name{Local: "testcase"},
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "name"}, Value: test.Name},
+			{Name: xml.Name{Local: "classname"}, Value: test.Classname},
+			{Name: xml.Name{Local: "time"}, Value: strconv.FormatFloat(test.Duration.Seconds(), 'f', -1, 64)},
+		},
+	}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch test.Status {
+	case StatusSkipped:
+		if err := writeResultElement(enc, "skipped", test.Message, test.Error); err != nil {
+			return err
+		}
+	case StatusFailed:
+		if err := writeFailureElement(enc, "failure", test); err != nil {
+			return err
+		}
+	case StatusError:
+		if err := writeFailureElement(enc, "error", test); err != nil {
+			return err
+		}
+	}
+
+	for _, retry := range test.Retries {
+		if err := writeResultElement(enc, "rerunFailure", retry.Message, retry); err != nil {
+			return err
+		}
+	}
+
+	if err := writeTextElement(enc, "system-out", test.SystemOut); err != nil {
+		return err
+	}
+	if err := writeTextElement(enc, "system-err", test.SystemErr); err != nil {
+		return err
+	}
+
+	return enc.EncodeToken(xml.EndElement{Name: start.Name})
+}
+
+// writeFailureElement writes a "failure" or "error" element, including the
+// file:line location of the assertion that triggered it, when known.
+func writeFailureElement(enc *xml.Encoder, local string, test Test) error {
+	attrs := []xml.Attr{
+		{Name: xml.Name{Local: "message"}, Value: test.Message},
+		{Name: xml.Name{Local: "type"}, Value: test.Error.Type},
+	}
+	if test.File != "" {
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "file"}, Value: test.File})
+	}
+	if test.Line != 0 {
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "line"}, Value: strconv.Itoa(test.Line)})
+	}
+
+	start := xml.StartElement{Name: xml.Name{Local: local}, Attr: attrs}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	if test.Error.Body != "" {
+		if err := enc.EncodeToken(xml.CharData(test.Error.Body)); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(xml.EndElement{Name: start.Name})
+}
+
+// writeResultElement writes a "skipped" or "rerunFailure"/"flakyFailure"
+// style element: message and type attributes plus an optional body.
+func writeResultElement(enc *xml.Encoder, local, message string, body Error) error {
+	start := xml.StartElement{
+		Name: xml.Name{Local: local},
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "message"}, Value: message},
+			{Name: xml.Name{Local: "type"}, Value: body.Type},
+		},
+	}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	if body.Body != "" {
+		if err := enc.EncodeToken(xml.CharData(body.Body)); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(xml.EndElement{Name: start.Name})
+}
+
+func writeTextElement(enc *xml.Encoder, local, content string) error {
+	if content == "" {
+		return nil
+	}
+
+	start := xml.StartElement{Name: xml.Name{Local: local}}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := enc.EncodeToken(xml.CharData(content)); err != nil {
+		return err
+	}
+	return enc.EncodeToken(xml.EndElement{Name: start.Name})
+}
@@ -0,0 +1,78 @@
+// Copyright Josh Komoroske. All rights reserved.
+// Use of this source code is governed by the MIT license,
+// a copy of which can be found in the LICENSE.txt file.
+// SPDX-License-Identifier: MIT
+
+package junit
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const flakyReport = `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="root" package="foo">
+	<testcase name="eventually-passes" classname="pkg" time="0.2">
+		<rerunFailure message="first attempt failed" type="AssertionError">boom</rerunFailure>
+	</testcase>
+	<testcase name="still-fails" classname="pkg" time="0.1">
+		<failure message="nope" type="AssertionError" file="widget_test.go" line="42">trace</failure>
+	</testcase>
+</testsuite>
+`
+
+func TestAggregateFlaky(t *testing.T) {
+	suites, err := IngestReader(strings.NewReader(flakyReport))
+	if err != nil {
+		t.Fatalf("IngestReader() returned error: %v", err)
+	}
+
+	suite := suites[0]
+	if !suite.Tests[0].Flaky {
+		t.Fatal("eventually-passes test should be marked Flaky")
+	}
+	if got, want := suite.Totals.Flaky, 1; got != want {
+		t.Fatalf("Totals.Flaky = %d, want %d", got, want)
+	}
+	if got, want := suite.Totals.Passed, 1; got != want {
+		t.Fatalf("Totals.Passed = %d, want %d", got, want)
+	}
+	if got, want := suite.Totals.Failed, 1; got != want {
+		t.Fatalf("Totals.Failed = %d, want %d", got, want)
+	}
+}
+
+func TestWriteXMLRoundTrip(t *testing.T) {
+	before, err := IngestReader(strings.NewReader(flakyReport))
+	if err != nil {
+		t.Fatalf("IngestReader() returned error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteXML(&buf, before); err != nil {
+		t.Fatalf("WriteXML() returned error: %v", err)
+	}
+
+	after, err := IngestReader(&buf)
+	if err != nil {
+		t.Fatalf("IngestReader() of re-serialized report returned error: %v", err)
+	}
+
+	if len(after) != 1 || len(after[0].Tests) != 2 {
+		t.Fatalf("re-ingested report has an unexpected shape: %+v", after)
+	}
+
+	flaky := after[0].Tests[0]
+	if !flaky.Flaky {
+		t.Fatal("re-ingested eventually-passes test should still be marked Flaky")
+	}
+	if len(flaky.Retries) != 1 || flaky.Retries[0].Body != "boom" {
+		t.Fatalf("re-ingested eventually-passes test lost its retries: %+v", flaky.Retries)
+	}
+
+	failed := after[0].Tests[1]
+	if failed.File != "widget_test.go" || failed.Line != 42 {
+		t.Fatalf("re-ingested still-fails test lost its file:line: %q:%d", failed.File, failed.Line)
+	}
+}